@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/template"
+
+	"github.com/spf13/cobra"
+)
+
+// Template used to print results per file, in the order materialize() put
+// them in (driven by --sort/--reverse/--top/--min-lines).
+const split = `
+{{range .}}{{.Path}} : Total {{.Total}} ; Code {{.Code}}({{.CodeS | printf "%.2f"}}%) ; Comments {{.Comment}}({{.CommentS | printf "%.2f"}}%) ; Blank {{.Blank}}({{.BlankS | printf "%.2f"}}%)
+{{else}}{{end}}`
+
+var countCmd = &cobra.Command{
+	Use:   "count [flags] file1 file2 ...",
+	Short: "Print code/comment/blank line counts for each matched file",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		a, errs := scan(args)
+		for _, e := range errs {
+			fmt.Println(fmt.Sprintf("Warning, couldn't process file %s : %s", e.filename, e.err.Error()))
+		}
+
+		if format == "" || format == "text" {
+			tmpl := template.Must(template.New("stats").Parse(split))
+			_ = tmpl.Execute(os.Stdout, materialize(a))
+		} else if err := writeReport(os.Stdout, buildReport(a), format, templateFile); err != nil {
+			fmt.Println("An error occurred ", err.Error())
+			os.Exit(1)
+		}
+
+		if err := checkThresholds(totalsOf(a)); err != nil {
+			fmt.Println("FAIL:", err.Error())
+			os.Exit(1)
+		}
+	},
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <old.json> <new.json>",
+	Short: "Compare two JSON reports produced by `aster count --format json` or `aster report --format json`",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		oldReport, err := loadReport(args[0])
+		if err != nil {
+			return err
+		}
+		newReport, err := loadReport(args[1])
+		if err != nil {
+			return err
+		}
+		printDiff(oldReport, newReport)
+		return nil
+	},
+}
+
+// loadReport reads and decodes a Report previously written by --format json.
+func loadReport(path string) (*Report, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// printDiff prints the per-file and total line deltas between two reports.
+func printDiff(oldReport, newReport *Report) {
+	oldByPath := make(map[string]FileReport, len(oldReport.Files))
+	for _, f := range oldReport.Files {
+		oldByPath[f.Path] = f
+	}
+	newByPath := make(map[string]FileReport, len(newReport.Files))
+	for _, f := range newReport.Files {
+		newByPath[f.Path] = f
+	}
+
+	seen := make(map[string]bool, len(oldByPath)+len(newByPath))
+	var paths []string
+	for _, files := range [][]FileReport{oldReport.Files, newReport.Files} {
+		for _, f := range files {
+			if !seen[f.Path] {
+				seen[f.Path] = true
+				paths = append(paths, f.Path)
+			}
+		}
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		o, n := oldByPath[p], newByPath[p]
+		if o == n {
+			continue
+		}
+		fmt.Printf("%s : code %+d ; comment %+d ; blank %+d ; total %+d\n",
+			p, n.Code-o.Code, n.Comment-o.Comment, n.Blank-o.Blank, n.Total-o.Total)
+	}
+
+	fmt.Printf("\nTotal : code %+d ; comment %+d ; blank %+d ; total %+d\n",
+		newReport.Totals.Code-oldReport.Totals.Code, newReport.Totals.Comment-oldReport.Totals.Comment,
+		newReport.Totals.Blank-oldReport.Totals.Blank, newReport.Totals.Total-oldReport.Totals.Total)
+}
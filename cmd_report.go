@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/template"
+
+	"github.com/spf13/cobra"
+)
+
+// Template used to print the aggregated summary
+const agg = `Total lines     {{.Total}}
+
+Code lines      {{.Code}} / {{.CodeS | printf "%.2f"}}%
+Comments        {{.Comment}} / {{.CommentS | printf "%.2f"}}%
+Blank lines     {{.Blank}} / {{.BlankS | printf "%.2f"}}%
+
+`
+
+var reportCmd = &cobra.Command{
+	Use:   "report [flags] file1 file2 ...",
+	Short: "Print an aggregated code/comment/blank summary across all matched files",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		a, errs := scan(args)
+		for _, e := range errs {
+			fmt.Println(fmt.Sprintf("Warning, couldn't process file %s : %s", e.filename, e.err.Error()))
+		}
+
+		totals := totalsOf(a)
+		if format == "" || format == "text" {
+			tmpl := template.Must(template.New("stats").Parse(agg))
+			_ = tmpl.Execute(os.Stdout, newStats(totals.Code, totals.Comment, totals.Blank))
+		} else if err := writeReport(os.Stdout, buildReport(a), format, templateFile); err != nil {
+			fmt.Println("An error occurred ", err.Error())
+			os.Exit(1)
+		}
+
+		if err := checkThresholds(totals); err != nil {
+			fmt.Println("FAIL:", err.Error())
+			os.Exit(1)
+		}
+	},
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+// asterVersion is stamped into JSON reports so downstream tooling can detect
+// schema drift across runs.
+const asterVersion = "dev"
+
+// buildReport flattens an Aggregator into the stable Report schema consumed
+// by --format json/csv/tsv/template and by `aster diff`. Files reflects
+// --sort/--reverse/--top/--min-lines; Totals always covers every scanned
+// file, regardless of those display filters.
+func buildReport(a *Aggregator) *Report {
+	materialized := materialize(a)
+	files := make([]FileReport, 0, len(materialized))
+	for _, f := range materialized {
+		files = append(files, FileReport{Path: f.Path, Code: f.Code, Comment: f.Comment, Blank: f.Blank, Total: f.Total})
+	}
+
+	return &Report{
+		Files:        files,
+		Totals:       totalsOf(a),
+		GeneratedAt:  time.Now().UTC().Format(time.RFC3339),
+		AsterVersion: asterVersion,
+	}
+}
+
+// totalsOf sums every file in a.Data, ignoring any display filters.
+func totalsOf(a *Aggregator) Totals {
+	var totals Totals
+	for _, s := range a.Data {
+		totals.Code += s.Code
+		totals.Comment += s.Comment
+		totals.Blank += s.Blank
+	}
+	totals.Total = totals.Code + totals.Comment + totals.Blank
+	return totals
+}
+
+// checkThresholds applies --fail-under-comment-ratio / --fail-over-total to
+// totals, returning a descriptive error if either is violated.
+func checkThresholds(totals Totals) error {
+	if failUnderRatio >= 0 {
+		var ratio float64
+		if totals.Total > 0 {
+			ratio = float64(totals.Comment) / float64(totals.Total) * 100
+		}
+		if ratio < failUnderRatio {
+			return fmt.Errorf("comment ratio %.2f%% is under the required %.2f%%", ratio, failUnderRatio)
+		}
+	}
+	if failOverTotal >= 0 && totals.Total > failOverTotal {
+		return fmt.Errorf("total line count %d exceeds the allowed %d", totals.Total, failOverTotal)
+	}
+	return nil
+}
+
+// writeReport renders report in the given structured format (json, csv, tsv
+// or template); the plain "text" format is rendered by each subcommand
+// itself since count and report use different templates.
+func writeReport(w io.Writer, report *Report, format string, templateFile string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case "csv", "tsv":
+		return writeDelimited(w, report, format)
+	case "template":
+		return writeTemplate(w, report, templateFile)
+	default:
+		return fmt.Errorf("unknown format %q, expected one of text, json, csv, tsv, template", format)
+	}
+}
+
+// writeDelimited writes report as a CSV (or, with format "tsv", tab-separated)
+// table of per-file counts followed by a trailing TOTAL row.
+func writeDelimited(w io.Writer, report *Report, format string) error {
+	cw := csv.NewWriter(w)
+	if format == "tsv" {
+		cw.Comma = '\t'
+	}
+	defer cw.Flush()
+
+	if err := cw.Write([]string{"path", "code", "comment", "blank", "total"}); err != nil {
+		return err
+	}
+	for _, f := range report.Files {
+		row := []string{f.Path, strconv.Itoa(f.Code), strconv.Itoa(f.Comment), strconv.Itoa(f.Blank), strconv.Itoa(f.Total)}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	return cw.Write([]string{
+		"TOTAL",
+		strconv.Itoa(report.Totals.Code),
+		strconv.Itoa(report.Totals.Comment),
+		strconv.Itoa(report.Totals.Blank),
+		strconv.Itoa(report.Totals.Total),
+	})
+}
+
+// writeTemplate renders report through the user-supplied Go text/template at
+// templateFile.
+func writeTemplate(w io.Writer, report *Report, templateFile string) error {
+	if templateFile == "" {
+		return errors.New("--template-file is required when --format=template")
+	}
+	data, err := ioutil.ReadFile(templateFile)
+	if err != nil {
+		return err
+	}
+	tmpl, err := template.New(filepath.Base(templateFile)).Parse(string(data))
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, report)
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// LanguageParser counts code, comment and blank lines from r. Implementations
+// are expected to understand enough of a language's grammar to avoid
+// treating "//" or "#" inside string literals as comments.
+type LanguageParser interface {
+	Count(r io.Reader) (code, comment, blank int, err error)
+}
+
+// LangConfig describes the comment and string syntax of a language for the
+// generic state-machine lexer.
+type LangConfig struct {
+	LineComment     []string // e.g. []string{"//"}
+	BlockStart      string   // e.g. "/*", empty if the language has none
+	BlockEnd        string   // e.g. "*/"
+	StringDelims    []string // delimiters whose content honours backslash-escaping, e.g. `"`, `'`
+	RawStringDelims []string // tried before StringDelims so that e.g. `"""` wins over `"`; backslash-escaping is honoured here too
+}
+
+// langConfigs maps a file extension (without the leading dot) to the syntax
+// table used by the generic lexer. Go is handled separately by goParser.
+var langConfigs = map[string]LangConfig{
+	"py":   {LineComment: []string{"#"}, StringDelims: []string{`"`, `'`}, RawStringDelims: []string{`"""`, `'''`}},
+	"js":   {LineComment: []string{"//"}, BlockStart: "/*", BlockEnd: "*/", StringDelims: []string{`"`, `'`}, RawStringDelims: []string{"`"}},
+	"ts":   {LineComment: []string{"//"}, BlockStart: "/*", BlockEnd: "*/", StringDelims: []string{`"`, `'`}, RawStringDelims: []string{"`"}},
+	"c":    {LineComment: []string{"//"}, BlockStart: "/*", BlockEnd: "*/", StringDelims: []string{`"`, `'`}},
+	"h":    {LineComment: []string{"//"}, BlockStart: "/*", BlockEnd: "*/", StringDelims: []string{`"`, `'`}},
+	"cpp":  {LineComment: []string{"//"}, BlockStart: "/*", BlockEnd: "*/", StringDelims: []string{`"`, `'`}},
+	"hpp":  {LineComment: []string{"//"}, BlockStart: "/*", BlockEnd: "*/", StringDelims: []string{`"`, `'`}},
+	"rs":   {LineComment: []string{"//"}, BlockStart: "/*", BlockEnd: "*/", StringDelims: []string{`"`, `'`}},
+	"sh":   {LineComment: []string{"#"}, StringDelims: []string{`"`, `'`}},
+	"bash": {LineComment: []string{"#"}, StringDelims: []string{`"`, `'`}},
+	"yml":  {LineComment: []string{"#"}, StringDelims: []string{`"`, `'`}},
+	"yaml": {LineComment: []string{"#"}, StringDelims: []string{`"`, `'`}},
+}
+
+// getParser returns the LanguageParser registered for extension, falling
+// back to the naive prefix-based scanner for unrecognised extensions.
+func getParser(extension string) LanguageParser {
+	if extension == "go" {
+		return goParser{}
+	}
+	if cfg, ok := langConfigs[extension]; ok {
+		return genericLexer{cfg}
+	}
+	return naiveParser{}
+}
+
+// goParser counts lines using go/parser and go/ast so that "//" or "/*"
+// appearing inside string literals is never mistaken for a comment.
+type goParser struct{}
+
+func (goParser) Count(r io.Reader) (code, comment, blank int, err error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", data, parser.ParseComments)
+	if err != nil {
+		// A file that doesn't parse (e.g. mid-edit, WIP syntax error) still
+		// has lines worth counting; fall back to the naive scanner rather
+		// than dropping it from every aggregate.
+		return naiveParser{}.Count(bytes.NewReader(data))
+	}
+
+	// Blank out the text of every comment group so that, once we re-split
+	// into lines below, a line that still has non-blank content after
+	// blanking necessarily contains code.
+	blanked := []byte(string(data))
+	cmap := ast.NewCommentMap(fset, file, file.Comments)
+	for _, group := range cmap.Comments() {
+		start := fset.Position(group.Pos()).Offset
+		end := fset.Position(group.End()).Offset
+		for i := start; i < end && i < len(blanked); i++ {
+			if blanked[i] != '\n' {
+				blanked[i] = ' '
+			}
+		}
+	}
+
+	sourceLines := strings.Split(string(data), "\n")
+	blankedLines := strings.Split(string(blanked), "\n")
+	if n := len(sourceLines); n > 0 && sourceLines[n-1] == "" {
+		// A trailing newline (the near-universal case) produces a spurious
+		// empty final element once split; drop it so it isn't counted as a
+		// blank line.
+		sourceLines = sourceLines[:n-1]
+		blankedLines = blankedLines[:n-1]
+	}
+	for i, raw := range sourceLines {
+		if strings.TrimSpace(raw) == "" {
+			blank++
+			continue
+		}
+		if strings.TrimSpace(blankedLines[i]) == "" {
+			comment++
+		} else {
+			code++
+		}
+	}
+	return code, comment, blank, nil
+}
+
+// lexState is the state of the genericLexer's scan across line boundaries.
+type lexState int
+
+const (
+	stateNormal lexState = iota
+	stateBlockComment
+	stateString
+)
+
+// genericLexer implements LanguageParser for languages described by a
+// LangConfig, via a small character-level state machine.
+type genericLexer struct {
+	cfg LangConfig
+}
+
+func (l genericLexer) Count(r io.Reader) (code, comment, blank int, err error) {
+	scanner := bufio.NewScanner(r)
+	state := stateNormal
+	var closing string
+	var escaped bool
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if state == stateNormal && strings.TrimSpace(line) == "" {
+			blank++
+			continue
+		}
+
+		hasCode, hasComment := l.classifyLine(line, &state, &closing, &escaped)
+		switch {
+		case hasCode:
+			code++
+		case hasComment:
+			comment++
+		default:
+			blank++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, 0, err
+	}
+	return code, comment, blank, nil
+}
+
+// classifyLine scans a single line character by character, carrying state
+// (inside a block comment or a string literal) across the call boundary.
+func (l genericLexer) classifyLine(line string, state *lexState, closing *string, escaped *bool) (hasCode, hasComment bool) {
+	i := 0
+	for i < len(line) {
+		switch *state {
+		case stateBlockComment:
+			hasComment = true
+			idx := strings.Index(line[i:], l.cfg.BlockEnd)
+			if idx == -1 {
+				return hasCode, hasComment
+			}
+			i += idx + len(l.cfg.BlockEnd)
+			*state = stateNormal
+
+		case stateString:
+			hasCode = true
+			closed := false
+			for ; i < len(line); i++ {
+				c := line[i : i+1]
+				if *escaped {
+					*escaped = false
+					continue
+				}
+				if c == `\` {
+					*escaped = true
+					continue
+				}
+				if strings.HasPrefix(line[i:], *closing) {
+					i += len(*closing)
+					*state = stateNormal
+					closed = true
+					break
+				}
+			}
+			if !closed {
+				return hasCode, hasComment
+			}
+
+		default: // stateNormal
+			rest := line[i:]
+			if lc := matchPrefix(rest, l.cfg.LineComment); lc != "" {
+				hasComment = true
+				return hasCode, hasComment
+			}
+			if l.cfg.BlockStart != "" && strings.HasPrefix(rest, l.cfg.BlockStart) {
+				hasComment = true
+				*state = stateBlockComment
+				i += len(l.cfg.BlockStart)
+				continue
+			}
+			if d := matchPrefix(rest, l.cfg.RawStringDelims); d != "" {
+				hasCode = true
+				*state = stateString
+				*closing = d
+				*escaped = false
+				i += len(d)
+				continue
+			}
+			if d := matchPrefix(rest, l.cfg.StringDelims); d != "" {
+				hasCode = true
+				*state = stateString
+				*closing = d
+				*escaped = false
+				i += len(d)
+				continue
+			}
+			if rest[0] != ' ' && rest[0] != '\t' {
+				hasCode = true
+			}
+			i++
+		}
+	}
+	return hasCode, hasComment
+}
+
+// matchPrefix returns the first of delims that prefixes s, or "" if none do.
+func matchPrefix(s string, delims []string) string {
+	for _, d := range delims {
+		if d != "" && strings.HasPrefix(s, d) {
+			return d
+		}
+	}
+	return ""
+}
+
+// naiveParser is the original prefix-based heuristic, kept as a fallback for
+// extensions with no dedicated LanguageParser.
+type naiveParser struct{}
+
+func (naiveParser) Count(r io.Reader) (code, comment, blank int, err error) {
+	state := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if state {
+			if strings.HasSuffix(line, "*/") {
+				state = false
+			}
+			comment += 1
+		} else {
+			if strings.HasPrefix(line, "//") || strings.HasPrefix(line, "#") {
+				comment += 1
+			} else if strings.HasPrefix(line, "/*") {
+				comment += 1
+				state = true
+			} else if line == "" {
+				blank += 1
+			} else {
+				code += 1
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, 0, err
+	}
+	return code, comment, blank, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGoParserCount(t *testing.T) {
+	cases := []struct {
+		name                        string
+		src                         string
+		code, comment, blank, total int
+	}{
+		{
+			name: "simple",
+			src:  "package main\n\nfunc main() {\n}\n",
+			code: 3, comment: 0, blank: 1, total: 4,
+		},
+		{
+			name: "comment slash in string is not a comment",
+			src:  "package main\n\nvar s = \"not // a comment\"\n",
+			code: 2, comment: 0, blank: 1, total: 3,
+		},
+		{
+			name: "block comment spanning multiple lines",
+			src:  "package main\n\n/*\nblock\ncomment\n*/\nvar x = 1\n",
+			code: 2, comment: 4, blank: 1, total: 7,
+		},
+		{
+			name: "mid-line trailing comment counts as code",
+			src:  "package main\n\nvar x = 1 // trailing\n",
+			code: 2, comment: 0, blank: 1, total: 3,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			code, comment, blank, err := goParser{}.Count(strings.NewReader(c.src))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if code != c.code || comment != c.comment || blank != c.blank {
+				t.Fatalf("got code=%d comment=%d blank=%d, want code=%d comment=%d blank=%d",
+					code, comment, blank, c.code, c.comment, c.blank)
+			}
+			if total := code + comment + blank; total != c.total {
+				t.Fatalf("got total=%d, want %d", total, c.total)
+			}
+		})
+	}
+}
+
+func TestGoParserFallsBackOnSyntaxError(t *testing.T) {
+	src := "package main\n\nfunc main( {\n"
+	code, comment, blank, err := goParser{}.Count(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("expected fallback to naiveParser instead of an error, got: %v", err)
+	}
+	if code+comment+blank == 0 {
+		t.Fatalf("expected the unparsable file to still be counted, got all zero")
+	}
+}
+
+func TestGenericLexerCount(t *testing.T) {
+	cases := []struct {
+		name                 string
+		ext                  string
+		src                  string
+		code, comment, blank int
+	}{
+		{
+			name: "python hash in triple-quoted string is not a comment",
+			ext:  "py",
+			src:  "x = \"\"\"\n# not a comment\n\"\"\"\n",
+			code: 3, comment: 0, blank: 0,
+		},
+		{
+			name: "python line comment",
+			ext:  "py",
+			src:  "# a real comment\nx = 1\n",
+			code: 1, comment: 1, blank: 0,
+		},
+		{
+			name: "js backtick raw string containing //",
+			ext:  "js",
+			src:  "const s = `not // a comment`\n",
+			code: 1, comment: 0, blank: 0,
+		},
+		{
+			name: "js block comment",
+			ext:  "js",
+			src:  "/* block\ncomment */\nconst x = 1\n",
+			code: 1, comment: 2, blank: 0,
+		},
+		{
+			name: "blank lines",
+			ext:  "c",
+			src:  "int x;\n\n\n",
+			code: 1, comment: 0, blank: 2,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg, ok := langConfigs[c.ext]
+			if !ok {
+				t.Fatalf("no LangConfig registered for extension %q", c.ext)
+			}
+			code, comment, blank, err := genericLexer{cfg}.Count(strings.NewReader(c.src))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if code != c.code || comment != c.comment || blank != c.blank {
+				t.Fatalf("got code=%d comment=%d blank=%d, want code=%d comment=%d blank=%d",
+					code, comment, blank, c.code, c.comment, c.blank)
+			}
+		})
+	}
+}
+
+func TestNaiveParserCount(t *testing.T) {
+	src := "// comment\ncode\n\n"
+	code, comment, blank, err := naiveParser{}.Count(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code != 1 || comment != 1 || blank != 1 {
+		t.Fatalf("got code=%d comment=%d blank=%d, want code=1 comment=1 blank=1", code, comment, blank)
+	}
+}
+
+func TestGetParserDispatch(t *testing.T) {
+	if _, ok := getParser("go").(goParser); !ok {
+		t.Fatalf("expected getParser(\"go\") to return a goParser")
+	}
+	if _, ok := getParser("py").(genericLexer); !ok {
+		t.Fatalf("expected getParser(\"py\") to return a genericLexer")
+	}
+	if _, ok := getParser("unknownext").(naiveParser); !ok {
+		t.Fatalf("expected getParser of an unregistered extension to fall back to naiveParser")
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+// FileReport is the JSON representation of one file's line counts, as
+// produced by `aster count --format json` (see the structured-output
+// request) and consumed here by `aster diff`.
+type FileReport struct {
+	Path    string `json:"path"`
+	Code    int    `json:"code"`
+	Comment int    `json:"comment"`
+	Blank   int    `json:"blank"`
+	Total   int    `json:"total"`
+}
+
+// Totals is the JSON representation of a report's aggregated counts.
+type Totals struct {
+	Code    int `json:"code"`
+	Comment int `json:"comment"`
+	Blank   int `json:"blank"`
+	Total   int `json:"total"`
+}
+
+// Report is the stable JSON schema emitted by `aster count/report --format
+// json` and read back by `aster diff`.
+type Report struct {
+	Files        []FileReport `json:"files"`
+	Totals       Totals       `json:"totals"`
+	GeneratedAt  string       `json:"generated_at"`
+	AsterVersion string       `json:"aster_version"`
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"sort"
+	"sync"
+)
+
+// A struct to display info about a file
+type Stats struct {
+	Code     int
+	Comment  int
+	Blank    int
+	Total    int
+	CodeS    float64
+	CommentS float64
+	BlankS   float64
+}
+
+// Initialize a new Stats struct
+func newStats(code, comment, blank int) *Stats {
+	total := code + comment + blank
+	if total == 0 {
+		total = 1
+	}
+	return &Stats{
+		code,
+		comment,
+		blank,
+		code + comment + blank,
+		float64(code) / float64(total) * 100,
+		float64(comment) / float64(total) * 100,
+		float64(blank) / float64(total) * 100,
+	}
+}
+
+// Stores the temporary results here
+type Aggregator struct {
+	mu   sync.Mutex
+	Data map[string]*Stats
+}
+
+// Set records the stats for filename, safe for concurrent use
+func (a *Aggregator) Set(filename string, s *Stats) {
+	a.mu.Lock()
+	a.Data[filename] = s
+	a.mu.Unlock()
+}
+
+// fileResult carries one worker's outcome back to the collector
+type fileResult struct {
+	filename string
+	stats    *Stats
+}
+
+// FileStat pairs a file's Stats with its path, for the sorted/filtered
+// tabular views (--sort, --reverse, --top, --min-lines).
+type FileStat struct {
+	Path string
+	*Stats
+}
+
+// materialize flattens a.Data into a []FileStat, dropping files under
+// --min-lines and applying --sort/--reverse/--top. This is the one place
+// that turns the nondeterministic map iteration order of Aggregator.Data
+// into a stable, orderable slice.
+func materialize(a *Aggregator) []FileStat {
+	files := make([]FileStat, 0, len(a.Data))
+	for path, s := range a.Data {
+		if s.Total < minLines {
+			continue
+		}
+		files = append(files, FileStat{path, s})
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		less := lessFileStat(files[i], files[j])
+		if reverseSort {
+			return !less
+		}
+		return less
+	})
+
+	if topN > 0 && topN < len(files) {
+		files = files[:topN]
+	}
+	return files
+}
+
+// lessFileStat orders two FileStat by the --sort key, breaking ties on Path
+// so that files sharing a key still sort into a deterministic order instead
+// of whatever order materialize happened to range a.Data in.
+func lessFileStat(a, b FileStat) bool {
+	switch sortBy {
+	case "code":
+		if a.Code != b.Code {
+			return a.Code < b.Code
+		}
+	case "comment":
+		if a.Comment != b.Comment {
+			return a.Comment < b.Comment
+		}
+	case "blank":
+		if a.Blank != b.Blank {
+			return a.Blank < b.Blank
+		}
+	case "total":
+		if a.Total != b.Total {
+			return a.Total < b.Total
+		}
+	case "ratio":
+		if a.CommentS != b.CommentS {
+			return a.CommentS < b.CommentS
+		}
+	}
+	return a.Path < b.Path
+}
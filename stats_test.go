@@ -0,0 +1,33 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMaterializeOrdersTiesDeterministically(t *testing.T) {
+	origSortBy, origReverse, origTop, origMin := sortBy, reverseSort, topN, minLines
+	defer func() {
+		sortBy, reverseSort, topN, minLines = origSortBy, origReverse, origTop, origMin
+	}()
+	sortBy, reverseSort, topN, minLines = "total", false, 0, 0
+
+	a := &Aggregator{Data: map[string]*Stats{
+		"c.go": newStats(1, 1, 0),
+		"a.go": newStats(1, 1, 0),
+		"d.go": newStats(1, 1, 0),
+		"b.go": newStats(1, 1, 0),
+	}}
+
+	want := []string{"a.go", "b.go", "c.go", "d.go"}
+	for i := 0; i < 20; i++ {
+		files := materialize(a)
+		var got []string
+		for _, f := range files {
+			got = append(got, f.Path)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("run %d: got %v, want %v", i, got, want)
+		}
+	}
+}
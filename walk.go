@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// fileError associates a filename with the error encountered while reading it
+type fileError struct {
+	filename string
+	err      error
+}
+
+// ignorePattern is a single gitignore-style glob together with the directory
+// it was declared relative to (the directory holding the .gitignore /
+// .asterignore, or the scan root for patterns read from --exclude-file).
+type ignorePattern struct {
+	glob string
+	base string
+}
+
+// scan walks args, counts every matched file through the concurrent pipeline
+// and returns the populated Aggregator together with any per-file errors.
+func scan(args []string) (*Aggregator, []fileError) {
+	extensions := strings.Split(extension, ",")
+	dirs := strings.Split(dir, ",")
+
+	var basePatterns []string
+	if excludeFile != "" {
+		patterns, err := readIgnoreFile(excludeFile)
+		if err != nil {
+			fmt.Println(fmt.Sprintf("Warning, couldn't read exclude file %s : %s", excludeFile, err.Error()))
+		}
+		basePatterns = patterns
+	}
+
+	a := &Aggregator{Data: make(map[string]*Stats)}
+	errs := run(args, extensions, dirs, basePatterns, a)
+	return a, errs
+}
+
+// run drives the producer/worker/collector pipeline: a producer walks args and
+// feeds candidate paths into pathsChan, a pool of workers reads paths off the
+// channel and counts lines, and a collector populates a under a mutex. It
+// blocks until every file has been processed and returns the errors collected
+// along the way.
+func run(args []string, extensions []string, dirs []string, basePatterns []string, a *Aggregator) []fileError {
+	pathsChan := make(chan string, 100)
+	resultsChan := make(chan fileResult, 100)
+	errChan := make(chan fileError, 100)
+
+	go produce(args, extensions, dirs, basePatterns, pathsChan)
+
+	n := workers
+	if n < 1 {
+		fmt.Println(fmt.Sprintf("Warning, --workers must be at least 1, got %d : using 1 instead", n))
+		n = 1
+	}
+
+	var workersWg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		workersWg.Add(1)
+		go func() {
+			defer workersWg.Done()
+			for path := range pathsChan {
+				code, comment, blank, err := visitFile(path)
+				if err != nil {
+					errChan <- fileError{path, err}
+					continue
+				}
+				resultsChan <- fileResult{path, newStats(code, comment, blank)}
+			}
+		}()
+	}
+
+	go func() {
+		workersWg.Wait()
+		close(resultsChan)
+		close(errChan)
+	}()
+
+	var collectorWg sync.WaitGroup
+	collectorWg.Add(1)
+	go func() {
+		defer collectorWg.Done()
+		for res := range resultsChan {
+			a.Set(res.filename, res.stats)
+		}
+	}()
+
+	var errs []fileError
+	for e := range errChan {
+		errs = append(errs, e)
+	}
+	collectorWg.Wait()
+
+	return errs
+}
+
+// produce walks args (recursively when -r is set) and pushes candidate file
+// paths matching extensions into paths, closing it once done.
+func produce(args []string, extensions []string, dirs []string, basePatterns []string, paths chan<- string) {
+	defer close(paths)
+	for _, filename := range args {
+		if recursive {
+			var rootDev uint64
+			if oneFileSystem {
+				if info, err := os.Lstat(filename); err == nil {
+					rootDev = deviceOf(info)
+				}
+			}
+			rooted := make([]ignorePattern, 0, len(basePatterns))
+			for _, p := range basePatterns {
+				rooted = append(rooted, ignorePattern{glob: p, base: filename})
+			}
+			walk(filename, extensions, dirs, rooted, rootDev, paths)
+		} else {
+			queueFile(filename, extensions, paths)
+		}
+	}
+}
+
+// walk recursively descends into path, skipping directories excluded via
+// -d/--exclude-dirs, .gitignore/.asterignore patterns inherited from parent
+// directories, and (with --one-file-system) directories on another device
+// than rootDev.
+func walk(path string, extensions []string, excludeDirs []string, inherited []ignorePattern, rootDev uint64, paths chan<- string) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		fmt.Println(fmt.Sprintf("Warning, couldn't process file %s : %s", path, err.Error()))
+		return
+	}
+
+	if !info.IsDir() {
+		if !matchesIgnorePatterns(inherited, path, false) {
+			queueFile(path, extensions, paths)
+		}
+		return
+	}
+
+	if matchesExcludeDirs(excludeDirs, path) || matchesIgnorePatterns(inherited, path, true) {
+		return
+	}
+	if oneFileSystem && rootDev != 0 && deviceOf(info) != rootDev {
+		return
+	}
+
+	patterns := inherited
+	for _, ignoreFile := range []string{".gitignore", ".asterignore"} {
+		if more, err := readIgnoreFile(filepath.Join(path, ignoreFile)); err == nil {
+			for _, g := range more {
+				patterns = append(patterns, ignorePattern{glob: g, base: path})
+			}
+		}
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		fmt.Println(fmt.Sprintf("Warning, couldn't process file %s : %s", path, err.Error()))
+		return
+	}
+	for _, entry := range entries {
+		walk(filepath.Join(path, entry.Name()), extensions, excludeDirs, patterns, rootDev, paths)
+	}
+}
+
+// matchesExcludeDirs reports whether path matches one of the comma-separated
+// -d/--exclude-dirs globs, tried both as a full-path glob and as a basename
+// glob so that a plain name such as "vendor" keeps working as it always did.
+func matchesExcludeDirs(patterns []string, path string) bool {
+	base := filepath.Base(path)
+	for _, p := range patterns {
+		if p == "" {
+			continue
+		}
+		if matched, _ := filepath.Match(p, path); matched {
+			return true
+		}
+		if matched, _ := filepath.Match(p, base); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesIgnorePatterns reports whether path (a directory when isDir is
+// true, a regular file otherwise) matches any gitignore-style pattern. A
+// trailing "/" marks a directory-only pattern (e.g. "vendor/", "dist/") and
+// is stripped before matching; such a pattern never matches a file. A
+// slash-less pattern (e.g. "vendor") matches path's basename at any depth,
+// as gitignore does. A pattern containing a "/" (with or without a leading
+// "/") is anchored: it is matched against path's location relative to the
+// directory the pattern was declared in (pattern.base), not against the
+// absolute path.
+func matchesIgnorePatterns(patterns []ignorePattern, path string, isDir bool) bool {
+	for _, p := range patterns {
+		glob := p.glob
+		if glob == "" {
+			continue
+		}
+		if dirOnly := strings.HasSuffix(glob, "/"); dirOnly {
+			if !isDir {
+				continue
+			}
+			glob = strings.TrimSuffix(glob, "/")
+		}
+		anchored := strings.TrimPrefix(glob, "/")
+		if anchored == glob && !strings.Contains(glob, "/") {
+			if matched, _ := filepath.Match(glob, filepath.Base(path)); matched {
+				return true
+			}
+			continue
+		}
+		rel, err := filepath.Rel(p.base, path)
+		if err != nil {
+			continue
+		}
+		if matched, _ := filepath.Match(anchored, filepath.ToSlash(rel)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// readIgnoreFile reads a gitignore-style file, one glob pattern per line,
+// skipping blank lines and "#" comments.
+func readIgnoreFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// deviceOf returns the device number backing info, used by --one-file-system
+// to detect when a walk would cross a filesystem boundary.
+func deviceOf(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Dev)
+	}
+	return 0
+}
+
+// queueFile stats filename and, if it is not a directory and matches
+// extensions, sends it to paths.
+func queueFile(filename string, extensions []string, paths chan<- string) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		fmt.Println(fmt.Sprintf("Warning, couldn't process file %s : %s", filename, err.Error()))
+		return
+	}
+	if info.IsDir() {
+		return
+	}
+	for _, ext := range extensions {
+		if ext == "" || strings.HasSuffix(filename, "."+ext) {
+			paths <- filename
+			return
+		}
+	}
+}
+
+// Parse a file. The actual counting is delegated to the LanguageParser
+// registered for the file's extension; most of them stream the file line by
+// line, but goParser reads a .go file into memory whole before parsing it.
+func visitFile(filename string) (code int, comment int, blank int, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer f.Close()
+
+	ext := strings.TrimPrefix(filepath.Ext(filename), ".")
+	return getParser(ext).Count(f)
+}
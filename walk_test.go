@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchesIgnorePatterns(t *testing.T) {
+	cases := []struct {
+		name     string
+		patterns []ignorePattern
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		{
+			name:     "trailing slash pattern matches the directory",
+			patterns: []ignorePattern{{glob: "node_modules/", base: "root"}},
+			path:     filepath.Join("root", "node_modules"),
+			isDir:    true,
+			want:     true,
+		},
+		{
+			name:     "trailing slash pattern does not match a same-named file",
+			patterns: []ignorePattern{{glob: "node_modules/", base: "root"}},
+			path:     filepath.Join("root", "node_modules"),
+			isDir:    false,
+			want:     false,
+		},
+		{
+			name:     "trailing slash pattern does not match a file by itself: pruning the directory is walk's job",
+			patterns: []ignorePattern{{glob: "node_modules/", base: "root"}},
+			path:     filepath.Join("root", "node_modules", "pkg.js"),
+			isDir:    false,
+			want:     false,
+		},
+		{
+			name:     "slash-less pattern matches basename at any depth",
+			patterns: []ignorePattern{{glob: "vendor", base: "root"}},
+			path:     filepath.Join("root", "a", "b", "vendor"),
+			isDir:    true,
+			want:     true,
+		},
+		{
+			name:     "anchored pattern only matches at its declaring directory",
+			patterns: []ignorePattern{{glob: "/build", base: filepath.Join("root", "sub")}},
+			path:     filepath.Join("root", "build"),
+			isDir:    true,
+			want:     false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := matchesIgnorePatterns(c.patterns, c.path, c.isDir); got != c.want {
+				t.Fatalf("matchesIgnorePatterns(%q, isDir=%v) = %v, want %v", c.path, c.isDir, got, c.want)
+			}
+		})
+	}
+}
+
+func TestWalkInheritsNestedIgnoreFiles(t *testing.T) {
+	root := t.TempDir()
+
+	mustWriteFile(t, filepath.Join(root, ".gitignore"), "node_modules/\n")
+	mustMkdir(t, filepath.Join(root, "node_modules"))
+	mustWriteFile(t, filepath.Join(root, "node_modules", "pkg.js"), "var x = 1\n")
+
+	mustMkdir(t, filepath.Join(root, "sub"))
+	mustWriteFile(t, filepath.Join(root, "sub", ".gitignore"), "dist/\n")
+	mustMkdir(t, filepath.Join(root, "sub", "dist"))
+	mustWriteFile(t, filepath.Join(root, "sub", "dist", "out.js"), "var y = 2\n")
+	mustWriteFile(t, filepath.Join(root, "sub", "kept.js"), "var z = 3\n")
+
+	paths := make(chan string, 10)
+	walk(root, []string{"js"}, nil, nil, 0, paths)
+	close(paths)
+
+	var got []string
+	for p := range paths {
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, filepath.ToSlash(rel))
+	}
+
+	want := "sub/kept.js"
+	if len(got) != 1 || got[0] != want {
+		t.Fatalf("got %v, want [%s]", got, want)
+	}
+}
+
+func mustWriteFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func mustMkdir(t *testing.T, path string) {
+	t.Helper()
+	if err := os.Mkdir(path, 0o755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}